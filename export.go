@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// requestYearTables returns the years present in the database, derived
+// from the requests_YYYY tables created by createYearTable.
+func requestYearTables(db *sql.DB) ([]int, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type='table' AND name LIKE 'requests\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying year tables: %w", err)
+	}
+	defer rows.Close()
+
+	var years []int
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning table name: %w", err)
+		}
+		var year int
+		if _, err := fmt.Sscanf(name, "requests_%d", &year); err != nil {
+			continue
+		}
+		years = append(years, year)
+	}
+	return years, rows.Err()
+}
+
+// requestFeature is a single Cityworks request plus the category/outcome
+// rows from its request_fields_YYYY table, shaped for GeoJSON/GeoPackage
+// export.
+type requestFeature struct {
+	ID            string
+	Initiated     string
+	Closed        string
+	FirstObserved string
+	Description   string
+	Initiator     string
+	Priority      string
+	Address       string
+	Community     string
+	District      string
+	Category      string
+	Resolution    string
+	Latitude      float64
+	Longitude     float64
+	Status        string
+	Department    string
+	WorkOrder     string
+	ProjectName   string
+	Fields        []requestFeatureField
+}
+
+type requestFeatureField struct {
+	CategoryID int    `json:"category_id"`
+	Category   string `json:"category"`
+	Outcome    string `json:"outcome"`
+}
+
+// yearFeatures loads every request in requests_YYYY for the given year,
+// nesting its request_fields_YYYY rows under Fields.
+func yearFeatures(db *sql.DB, year int) ([]requestFeature, error) {
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, initiated, closed, first_observed, description, initiator, priority, address, community, district, category, resolution, latitude, longitude, status, department, work_order, project_name FROM requests_%d ORDER BY id`, year))
+	if err != nil {
+		return nil, fmt.Errorf("querying requests: %w", err)
+	}
+	defer rows.Close()
+
+	var features []requestFeature
+	for rows.Next() {
+		var f requestFeature
+		var lat, lon sql.NullString
+		if err := rows.Scan(&f.ID, &f.Initiated, &f.Closed, &f.FirstObserved, &f.Description, &f.Initiator, &f.Priority, &f.Address, &f.Community, &f.District, &f.Category, &f.Resolution, &lat, &lon, &f.Status, &f.Department, &f.WorkOrder, &f.ProjectName); err != nil {
+			return nil, fmt.Errorf("scanning request: %w", err)
+		}
+		// A request geocoded with no coordinates is stored as the empty
+		// string, which does not parse as a float; treat it the same as
+		// 0,0, which callers already take to mean "no geometry".
+		f.Latitude, _ = strconv.ParseFloat(lat.String, 64)
+		f.Longitude, _ = strconv.ParseFloat(lon.String, 64)
+		features = append(features, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fieldRows, err := db.Query(fmt.Sprintf(`SELECT id, category_id, category, outcome FROM request_fields_%d ORDER BY id, category_id`, year))
+	if err != nil {
+		return nil, fmt.Errorf("querying fields: %w", err)
+	}
+	defer fieldRows.Close()
+
+	byID := make(map[string]*requestFeature, len(features))
+	for i := range features {
+		byID[features[i].ID] = &features[i]
+	}
+	for fieldRows.Next() {
+		var id string
+		var field requestFeatureField
+		if err := fieldRows.Scan(&id, &field.CategoryID, &field.Category, &field.Outcome); err != nil {
+			return nil, fmt.Errorf("scanning field: %w", err)
+		}
+		if f, ok := byID[id]; ok {
+			f.Fields = append(f.Fields, field)
+		}
+	}
+	return features, fieldRows.Err()
+}
+
+func (f requestFeature) properties() map[string]any {
+	return map[string]any{
+		"id":             f.ID,
+		"initiated":      f.Initiated,
+		"closed":         f.Closed,
+		"first_observed": f.FirstObserved,
+		"description":    f.Description,
+		"initiator":      f.Initiator,
+		"priority":       f.Priority,
+		"address":        f.Address,
+		"community":      f.Community,
+		"district":       f.District,
+		"category":       f.Category,
+		"resolution":     f.Resolution,
+		"status":         f.Status,
+		"department":     f.Department,
+		"work_order":     f.WorkOrder,
+		"project_name":   f.ProjectName,
+		"fields":         f.Fields,
+	}
+}
+
+type geoJSONFeature struct {
+	Type       string         `json:"type"`
+	Geometry   *geoJSONPoint  `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type      string           `json:"type"`
+	BBox      []float64        `json:"bbox,omitempty"`
+	Generated string           `json:"generated"`
+	Features  []geoJSONFeature `json:"features"`
+}
+
+// exportGeoJSON walks every requests_YYYY table and writes a single
+// combined RFC 7946 GeoJSON FeatureCollection to path. Requests without a
+// usable latitude/longitude are exported with a null geometry.
+func exportGeoJSON(db *sql.DB, path string) error {
+	years, err := requestYearTables(db)
+	if err != nil {
+		return err
+	}
+
+	fc := geoJSONFeatureCollection{
+		Type:      "FeatureCollection",
+		Generated: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var minX, minY, maxX, maxY float64
+	haveBBox := false
+
+	for _, year := range years {
+		features, err := yearFeatures(db, year)
+		if err != nil {
+			return fmt.Errorf("loading %d requests: %w", year, err)
+		}
+		for _, f := range features {
+			gf := geoJSONFeature{
+				Type:       "Feature",
+				Properties: f.properties(),
+			}
+			if f.Longitude != 0 || f.Latitude != 0 {
+				gf.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{f.Longitude, f.Latitude}}
+				if !haveBBox {
+					minX, minY, maxX, maxY = f.Longitude, f.Latitude, f.Longitude, f.Latitude
+					haveBBox = true
+				} else {
+					minX, maxX = math.Min(minX, f.Longitude), math.Max(maxX, f.Longitude)
+					minY, maxY = math.Min(minY, f.Latitude), math.Max(maxY, f.Latitude)
+				}
+			}
+			fc.Features = append(fc.Features, gf)
+		}
+	}
+	if haveBBox {
+		fc.BBox = []float64{minX, minY, maxX, maxY}
+	}
+
+	b, err := json.Marshal(fc)
+	if err != nil {
+		return fmt.Errorf("marshaling feature collection: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// gpkgSRSWGS84 is the EPSG:4326 (WGS 84) spatial reference ID used for
+// every geometry written to the GeoPackage.
+const gpkgSRSWGS84 = 4326
+
+// exportGeoPackage walks every requests_YYYY table and writes an OGC
+// GeoPackage to path containing a single "requests" features table with
+// Point geometries in EPSG:4326.
+func exportGeoPackage(db *sql.DB, path string) error {
+	years, err := requestYearTables(db)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing existing %s: %w", path, err)
+	}
+
+	gdb, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer gdb.Close()
+
+	// application_id/user_version mark the file as a GeoPackage per the OGC
+	// spec (0x47504B47 is 'GPKG'; 10300 is GeoPackage version 1.3); GDAL/OGR
+	// and QGIS key off these before looking at gpkg_contents.
+	if _, err := gdb.Exec(`PRAGMA application_id = 1196444487`); err != nil {
+		return fmt.Errorf("setting application_id: %w", err)
+	}
+	if _, err := gdb.Exec(`PRAGMA user_version = 10300`); err != nil {
+		return fmt.Errorf("setting user_version: %w", err)
+	}
+
+	if err := createGeoPackageSchema(gdb); err != nil {
+		return fmt.Errorf("creating geopackage schema: %w", err)
+	}
+
+	tx, err := gdb.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.Prepare(`INSERT INTO requests (geom, id, initiated, closed, first_observed, description, initiator, priority, address, community, district, category, resolution, status, department, work_order, project_name, fields) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+	if err != nil {
+		return fmt.Errorf("preparing insert: %w", err)
+	}
+
+	var minX, minY, maxX, maxY float64
+	haveBBox := false
+
+	for _, year := range years {
+		features, err := yearFeatures(db, year)
+		if err != nil {
+			return fmt.Errorf("loading %d requests: %w", year, err)
+		}
+		for _, f := range features {
+			var geom []byte
+			if f.Longitude != 0 || f.Latitude != 0 {
+				geom = gpkgPointBinary(f.Longitude, f.Latitude)
+				if !haveBBox {
+					minX, minY, maxX, maxY = f.Longitude, f.Latitude, f.Longitude, f.Latitude
+					haveBBox = true
+				} else {
+					minX, maxX = math.Min(minX, f.Longitude), math.Max(maxX, f.Longitude)
+					minY, maxY = math.Min(minY, f.Latitude), math.Max(maxY, f.Latitude)
+				}
+			}
+
+			fieldsJSON, err := json.Marshal(f.Fields)
+			if err != nil {
+				return fmt.Errorf("marshaling fields: %w", err)
+			}
+
+			if _, err := insert.Exec(geom, f.ID, f.Initiated, f.Closed, f.FirstObserved, f.Description, f.Initiator, f.Priority, f.Address, f.Community, f.District, f.Category, f.Resolution, f.Status, f.Department, f.WorkOrder, f.ProjectName, string(fieldsJSON)); err != nil {
+				return fmt.Errorf("inserting request %s: %w", f.ID, err)
+			}
+		}
+	}
+
+	if haveBBox {
+		if _, err := tx.Exec(`UPDATE gpkg_contents SET min_x=?, min_y=?, max_x=?, max_y=? WHERE table_name='requests'`, minX, minY, maxX, maxY); err != nil {
+			return fmt.Errorf("updating contents extent: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// createGeoPackageSchema creates the minimum set of gpkg_* tables required
+// for a reader to recognize the "requests" table as a features layer, plus
+// the table itself.
+func createGeoPackageSchema(db *sql.DB) error {
+	stmts := []string{
+		`CREATE TABLE gpkg_spatial_ref_sys (
+			srs_name TEXT NOT NULL,
+			srs_id INTEGER NOT NULL PRIMARY KEY,
+			organization TEXT NOT NULL,
+			organization_coordsys_id INTEGER NOT NULL,
+			definition TEXT NOT NULL,
+			description TEXT
+		)`,
+		`CREATE TABLE gpkg_contents (
+			table_name TEXT NOT NULL PRIMARY KEY,
+			data_type TEXT NOT NULL,
+			identifier TEXT UNIQUE,
+			description TEXT DEFAULT '',
+			last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+			min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE,
+			srs_id INTEGER,
+			FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+		)`,
+		`CREATE TABLE gpkg_geometry_columns (
+			table_name TEXT NOT NULL,
+			column_name TEXT NOT NULL,
+			geometry_type_name TEXT NOT NULL,
+			srs_id INTEGER NOT NULL,
+			z TINYINT NOT NULL,
+			m TINYINT NOT NULL,
+			PRIMARY KEY (table_name, column_name),
+			FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+			FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+		)`,
+		`INSERT INTO gpkg_spatial_ref_sys (srs_name, srs_id, organization, organization_coordsys_id, definition, description) VALUES
+			('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system'),
+			('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system'),
+			('WGS 84 geodetic', 4326, 'EPSG', 4326, 'GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]]', 'longitude/latitude coordinates in WGS 84')`,
+		fmt.Sprintf(`CREATE TABLE requests (
+			fid INTEGER PRIMARY KEY AUTOINCREMENT,
+			geom BLOB,
+			id TEXT, initiated DATETIME, closed DATETIME, first_observed DATETIME,
+			description TEXT, initiator TEXT, priority TEXT, address TEXT,
+			community TEXT, district TEXT, category TEXT, resolution TEXT,
+			status TEXT, department TEXT, work_order TEXT, project_name TEXT,
+			fields TEXT
+		)`),
+		`INSERT INTO gpkg_geometry_columns (table_name, column_name, geometry_type_name, srs_id, z, m) VALUES ('requests', 'geom', 'POINT', 4326, 0, 0)`,
+		fmt.Sprintf(`INSERT INTO gpkg_contents (table_name, data_type, identifier, srs_id) VALUES ('requests', 'features', 'requests', %d)`, gpkgSRSWGS84),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("executing %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// gpkgPointBinary encodes a single Point as a GeoPackage geometry blob: the
+// standard GeoPackageBinaryHeader (magic, version, flags, SRS ID) followed
+// by a little-endian WKB Point, per the OGC GeoPackage spec.
+func gpkgPointBinary(lon, lat float64) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('G')
+	buf.WriteByte('P')
+	buf.WriteByte(0) // version
+	buf.WriteByte(1) // flags: little-endian, no envelope, not empty
+	binary.Write(&buf, binary.LittleEndian, int32(gpkgSRSWGS84))
+
+	buf.WriteByte(1)                                   // WKB byte order: little-endian
+	binary.Write(&buf, binary.LittleEndian, uint32(1)) // WKB type: Point
+	binary.Write(&buf, binary.LittleEndian, lon)
+	binary.Write(&buf, binary.LittleEndian, lat)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestGpkgPointBinary(t *testing.T) {
+	lon, lat := -63.5752, 44.6488
+	b := gpkgPointBinary(lon, lat)
+
+	if len(b) != 8+21 {
+		t.Fatalf("len(b) = %d, want %d", len(b), 8+21)
+	}
+	if string(b[0:2]) != "GP" {
+		t.Errorf("magic = %q, want \"GP\"", b[0:2])
+	}
+	if b[2] != 0 {
+		t.Errorf("version byte = %d, want 0", b[2])
+	}
+	if b[3] != 1 {
+		t.Errorf("flags byte = %d, want 1", b[3])
+	}
+	if srs := int32(binary.LittleEndian.Uint32(b[4:8])); srs != gpkgSRSWGS84 {
+		t.Errorf("srs_id = %d, want %d", srs, gpkgSRSWGS84)
+	}
+
+	wkb := b[8:]
+	if wkb[0] != 1 {
+		t.Errorf("WKB byte order = %d, want 1 (little-endian)", wkb[0])
+	}
+	if typ := binary.LittleEndian.Uint32(wkb[1:5]); typ != 1 {
+		t.Errorf("WKB type = %d, want 1 (Point)", typ)
+	}
+	gotLon := math.Float64frombits(binary.LittleEndian.Uint64(wkb[5:13]))
+	gotLat := math.Float64frombits(binary.LittleEndian.Uint64(wkb[13:21]))
+	if gotLon != lon {
+		t.Errorf("lon = %v, want %v", gotLon, lon)
+	}
+	if gotLat != lat {
+		t.Errorf("lat = %v, want %v", gotLat, lat)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// trackableColumns are the requests_YYYY columns -track-columns is allowed
+// to name, matching the column set INSERT ... ON CONFLICT already updates,
+// minus latitude/longitude: those are stored as REAL, so the old value
+// comes back from record's sql.NullString scan reformatted by
+// database/sql's float->string conversion and almost never matches the
+// new value's raw text even when the coordinate hasn't changed.
+var trackableColumns = map[string]bool{
+	"initiated":    true,
+	"closed":       true,
+	"description":  true,
+	"initiator":    true,
+	"priority":     true,
+	"address":      true,
+	"community":    true,
+	"district":     true,
+	"category":     true,
+	"resolution":   true,
+	"status":       true,
+	"department":   true,
+	"work_order":   true,
+	"project_name": true,
+}
+
+// parseTrackColumns splits a comma-separated -track-columns flag value and
+// validates each entry against trackableColumns.
+func parseTrackColumns(s string) ([]string, error) {
+	var columns []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !trackableColumns[c] {
+			return nil, fmt.Errorf("not a trackable column: %q", c)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// historyTracker writes request_history_YYYY rows for any tracked column
+// whose value changes between syncs, comparing the incoming row against
+// what's already stored before each upsert.
+type historyTracker struct {
+	tx      *sql.Tx
+	columns []string
+
+	selectStmts map[int]*sql.Stmt
+	insertStmts map[int]*sql.Stmt
+}
+
+func newHistoryTracker(tx *sql.Tx, columns []string) *historyTracker {
+	return &historyTracker{
+		tx:          tx,
+		columns:     columns,
+		selectStmts: make(map[int]*sql.Stmt),
+		insertStmts: make(map[int]*sql.Stmt),
+	}
+}
+
+// record compares newValues (keyed by column name) against the row
+// currently stored for id in requests_YYYY, writing a request_history_YYYY
+// row for each tracked column that differs. A missing row (first time id
+// is seen) is not a change and records nothing.
+func (h *historyTracker) record(year int, id string, newValues map[string]string) error {
+	if len(h.columns) == 0 {
+		return nil
+	}
+
+	sel, err := h.selectStmt(year)
+	if err != nil {
+		return err
+	}
+
+	dest := make([]sql.NullString, len(h.columns))
+	destPtrs := make([]any, len(dest))
+	for i := range dest {
+		destPtrs[i] = &dest[i]
+	}
+
+	if err := sel.QueryRow(id).Scan(destPtrs...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("selecting existing row: %w", err)
+	}
+
+	var insert *sql.Stmt
+	observedAt := time.Now().UTC().Truncate(time.Millisecond)
+	for i, col := range h.columns {
+		old, next := dest[i].String, newValues[col]
+		if old == next {
+			continue
+		}
+		if insert == nil {
+			insert, err = h.insertStmt(year)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := insert.Exec(id, observedAt, col, old, next); err != nil {
+			return fmt.Errorf("inserting history row: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *historyTracker) selectStmt(year int) (*sql.Stmt, error) {
+	if stmt, ok := h.selectStmts[year]; ok {
+		return stmt, nil
+	}
+	stmt, err := h.tx.Prepare(fmt.Sprintf(`SELECT %s FROM requests_%d WHERE id = ?`, strings.Join(h.columns, ","), year))
+	if err != nil {
+		return nil, fmt.Errorf("preparing history select statement: %w", err)
+	}
+	h.selectStmts[year] = stmt
+	return stmt, nil
+}
+
+func (h *historyTracker) insertStmt(year int) (*sql.Stmt, error) {
+	if stmt, ok := h.insertStmts[year]; ok {
+		return stmt, nil
+	}
+	stmt, err := h.tx.Prepare(fmt.Sprintf(`INSERT INTO request_history_%d (id, observed_at, column_name, old_value, new_value) VALUES (?, ?, ?, ?, ?)`, year))
+	if err != nil {
+		return nil, fmt.Errorf("preparing history insert statement: %w", err)
+	}
+	h.insertStmts[year] = stmt
+	return stmt, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrackColumns(t *testing.T) {
+	got, err := parseTrackColumns("status, closed,priority")
+	if err != nil {
+		t.Fatalf("parseTrackColumns: %v", err)
+	}
+	want := []string{"status", "closed", "priority"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTrackColumns = %v, want %v", got, want)
+	}
+
+	if got, err := parseTrackColumns(""); err != nil || got != nil {
+		t.Errorf("parseTrackColumns(\"\") = %v, %v, want nil, nil", got, err)
+	}
+
+	if _, err := parseTrackColumns("status,not_a_column"); err == nil {
+		t.Error("parseTrackColumns(unknown column) = nil error, want error")
+	}
+
+	if _, err := parseTrackColumns("latitude"); err == nil {
+		t.Error("parseTrackColumns(latitude) = nil error, want error (excluded from trackableColumns)")
+	}
+}
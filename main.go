@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"strconv"
@@ -20,31 +21,80 @@ import (
 	_ "github.com/ncruces/go-sqlite3/embed"
 )
 
+// maxBackoff caps the exponential backoff used while polling for an export
+// and while retrying failed requests.
+const maxBackoff = 60 * time.Second
+
+// downloadOptions tunes how download polls the Hub export endpoint and
+// retries failed requests. Values are sourced from command-line flags so
+// long-running syncs can be adjusted without recompiling.
+type downloadOptions struct {
+	pollTimeout    time.Duration
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+// syncOptions bundles the flags that control a single sync run.
+type syncOptions struct {
+	dbPath        string
+	fieldsFile    string
+	download      downloadOptions
+	exportGeoJSON string
+	exportGPKG    string
+
+	source           string
+	requestsFile     string
+	featureServerURL string
+
+	trackColumns []string
+}
+
 func main() {
 	ctx := context.Background()
 
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	var dbPath string
-	fs.StringVar(&dbPath, "db", "data.db", "database file path")
-	var requestsFile, fieldsFile string
-	fs.StringVar(&requestsFile, "requests", "", "requests file path, otherwise download")
-	fs.StringVar(&fieldsFile, "fields", "", "fields file path, otherwise download")
+	var opts syncOptions
+	fs.StringVar(&opts.dbPath, "db", "data.db", "database file path")
+	fs.StringVar(&opts.requestsFile, "requests", "", "requests file path, otherwise download (csv source only)")
+	fs.StringVar(&opts.fieldsFile, "fields", "", "fields file path, otherwise download")
+	fs.DurationVar(&opts.download.pollTimeout, "poll-timeout", 10*time.Minute, "overall deadline for polling a Hub export to become ready")
+	fs.IntVar(&opts.download.maxRetries, "max-retries", 5, "maximum retries for a transient HTTP failure (5xx, network error, or 429)")
+	fs.DurationVar(&opts.download.initialBackoff, "initial-backoff", 2*time.Second, "initial backoff before retrying a failed request, doubled (with full jitter) on each attempt up to 60s")
+	fs.StringVar(&opts.exportGeoJSON, "export-geojson", "", "write a combined GeoJSON FeatureCollection of all requests to this path after syncing")
+	fs.StringVar(&opts.exportGPKG, "export-gpkg", "", "write an OGC GeoPackage of all requests to this path after syncing")
+	fs.StringVar(&opts.source, "source", "csv", "requests source to sync from: csv or featureserver")
+	fs.StringVar(&opts.featureServerURL, "featureserver-url", "", "FeatureServer layer URL, e.g. https://services.arcgis.com/.../FeatureServer/0 (required for -source=featureserver)")
+	var trackColumns string
+	fs.StringVar(&trackColumns, "track-columns", "status,closed,priority,resolution,department", "comma-separated requests_YYYY columns to record request_history_YYYY rows for when they change")
 	fs.Parse(os.Args[1:])
 
-	if err := run(ctx, dbPath, requestsFile, fieldsFile); err != nil {
+	columns, err := parseTrackColumns(trackColumns)
+	if err != nil {
+		log.Fatal(fmt.Errorf("parsing track-columns: %w", err))
+	}
+	opts.trackColumns = columns
+
+	if err := run(ctx, opts); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
-	db, err := sql.Open("sqlite3", "file:"+dbPath)
+func run(ctx context.Context, opts syncOptions) error {
+	db, err := sql.Open("sqlite3", "file:"+opts.dbPath)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	if _, err = db.Exec(`CREATE TABLE IF NOT EXISTS sync_state (requests_modified DATETIME)`); err != nil {
-		return fmt.Errorf("creating sync_state table: %w", err)
+	if err := runMigrations(db); err != nil {
+		return fmt.Errorf("migrating schema: %w", err)
 	}
 
 	halifax, err := time.LoadLocation("America/Halifax")
@@ -54,44 +104,29 @@ func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
 
 	yearTables := make(map[int]struct{})
 
-	var requests io.ReadCloser
-	var requestsModified time.Time
-	if requestsFile != "" {
-		requests, err = os.Open(requestsFile)
-		if err != nil {
-			return fmt.Errorf("opening data file: %w", err)
-		}
-	} else {
-		var modified time.Time
-		if err := db.QueryRow("SELECT requests_modified FROM sync_state").Scan(&modified); err != nil {
-			if !errors.Is(err, sql.ErrNoRows) {
-				return fmt.Errorf("selecting modified time: %w", err)
-			}
-		}
+	src, err := newSource(opts, halifax)
+	if err != nil {
+		return fmt.Errorf("configuring source: %w", err)
+	}
 
-		const requestsID = "d2b7dd138adb468293183926a1a7a81c"
-		r, modified, err := download(ctx, requestsID, modified)
-		if err != nil {
-			return fmt.Errorf("downloading data: %w", err)
-		}
-		if modified.IsZero() {
-			return nil
+	var lastModified time.Time
+	if err := db.QueryRow("SELECT requests_modified FROM sync_state").Scan(&lastModified); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("selecting modified time: %w", err)
 		}
-		requests = r
-		requestsModified = modified
 	}
-	defer requests.Close()
-
-	cr := csv.NewReader(requests)
 
-	// REQUEST_ID DATE_INITIATED DATE_CLOSED DESCRIPTION INITIATED_BY PRIORITY ADDRESS COMMUNITY DISTRICT REQUEST_CATEGORY RESOLUTION LATITUDE LONGITUDE STATUS DEPT_RESPONSIBILITY WORK_ORDER ObjectId PROJECT_NAME
-	header, err := cr.Read()
+	requestsModified, err := src.Modified(ctx)
 	if err != nil {
-		return fmt.Errorf("reading header: %w", err)
+		return fmt.Errorf("getting source modified time: %w", err)
 	}
-	headerIndices := make(map[string]int)
-	for i, h := range header {
-		headerIndices[h] = i
+	if !requestsModified.After(lastModified) {
+		return nil
+	}
+
+	records, err := src.Fetch(ctx, lastModified)
+	if err != nil {
+		return fmt.Errorf("fetching records: %w", err)
 	}
 
 	tx, err := db.Begin()
@@ -165,32 +200,29 @@ func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
 	}
 
 	requestYears := make(map[int]int) // request ID -> year
+	tracker := newHistoryTracker(tx, opts.trackColumns)
 
 	var n int
-	for {
-		row, err := cr.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		}
+	for rec, err := range records {
 		if err != nil {
-			return fmt.Errorf("reading row: %w", err)
+			return fmt.Errorf("reading record: %w", err)
 		}
 
-		requestID, err := strconv.Atoi(row[headerIndices["REQUEST_ID"]])
+		requestID, err := strconv.Atoi(rec["REQUEST_ID"])
 		if err != nil {
 			return fmt.Errorf("parsing request ID: %w", err)
 		}
 
 		const timeFormat = "1/2/2006 3:04:05 PM"
 		var initiatedValue string
-		initiated, err := time.ParseInLocation(timeFormat, row[headerIndices["DATE_INITIATED"]], halifax)
+		initiated, err := time.ParseInLocation(timeFormat, rec["DATE_INITIATED"], halifax)
 		if err != nil {
 			return fmt.Errorf("parsing initiated time: %w", err)
 		}
 		initiatedValue = initiated.UTC().Format(time.RFC3339)
 		var closed time.Time
 		var closedValue string
-		if v := row[headerIndices["DATE_CLOSED"]]; v != "" {
+		if v := rec["DATE_CLOSED"]; v != "" {
 			t, err := time.ParseInLocation(timeFormat, v, halifax)
 			if err != nil {
 				return fmt.Errorf("parsing closed time: %w", err)
@@ -201,7 +233,7 @@ func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
 
 		if initiated.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, halifax)) {
 			if closed.IsZero() {
-				return fmt.Errorf("invalid initiated time: %v %v", row[headerIndices["REQUEST_ID"]], initiated)
+				return fmt.Errorf("invalid initiated time: %v %v", rec["REQUEST_ID"], initiated)
 			}
 			initiated = closed
 		}
@@ -216,29 +248,51 @@ func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
 
 		requestYears[requestID] = year
 
+		newValues := map[string]string{
+			"initiated":    initiatedValue,
+			"closed":       closedValue,
+			"description":  rec["DESCRIPTION"],
+			"initiator":    rec["INITIATED_BY"],
+			"priority":     rec["PRIORITY"],
+			"address":      rec["ADDRESS"],
+			"community":    rec["COMMUNITY"],
+			"district":     rec["DISTRICT"],
+			"category":     rec["REQUEST_CATEGORY"],
+			"resolution":   rec["RESOLUTION"],
+			"latitude":     rec["LATITUDE"],
+			"longitude":    rec["LONGITUDE"],
+			"status":       rec["STATUS"],
+			"department":   rec["DEPT_RESPONSIBILITY"],
+			"work_order":   rec["WORK_ORDER"],
+			"project_name": rec["PROJECT_NAME"],
+		}
+		if err := tracker.record(year, rec["REQUEST_ID"], newValues); err != nil {
+			return fmt.Errorf("recording history: %w", err)
+		}
+
 		stmt, err := requestYearStmt(year)
 		if err != nil {
 			return fmt.Errorf("getting year statement: %w", err)
 		}
 
 		_, err = stmt.Exec(
-			row[headerIndices["REQUEST_ID"]],
+			rec["REQUEST_ID"],
 			initiatedValue,
 			closedValue,
-			row[headerIndices["DESCRIPTION"]],
-			row[headerIndices["INITIATED_BY"]],
-			row[headerIndices["PRIORITY"]],
-			row[headerIndices["ADDRESS"]],
-			row[headerIndices["COMMUNITY"]],
-			row[headerIndices["DISTRICT"]],
-			row[headerIndices["REQUEST_CATEGORY"]],
-			row[headerIndices["RESOLUTION"]],
-			row[headerIndices["LATITUDE"]],
-			row[headerIndices["LONGITUDE"]],
-			row[headerIndices["STATUS"]],
-			row[headerIndices["DEPT_RESPONSIBILITY"]],
-			row[headerIndices["WORK_ORDER"]],
-			row[headerIndices["PROJECT_NAME"]],
+			rec["DESCRIPTION"],
+			rec["INITIATED_BY"],
+			rec["PRIORITY"],
+			rec["ADDRESS"],
+			rec["COMMUNITY"],
+			rec["DISTRICT"],
+			rec["REQUEST_CATEGORY"],
+			rec["RESOLUTION"],
+			rec["LATITUDE"],
+			rec["LONGITUDE"],
+			rec["STATUS"],
+			rec["DEPT_RESPONSIBILITY"],
+			rec["WORK_ORDER"],
+			rec["PROJECT_NAME"],
 			time.Now().UTC().Truncate(time.Millisecond),
 		)
 		if err != nil {
@@ -261,115 +315,106 @@ func run(ctx context.Context, dbPath, requestsFile, fieldsFile string) error {
 		}
 	}
 
-	var fields io.ReadCloser
-	if fieldsFile != "" {
-		fields, err = os.Open(fieldsFile)
-		if err != nil {
-			return fmt.Errorf("opening fields file: %w", err)
-		}
+	// The categories/outcomes fields export only exists as a Hub CSV item;
+	// there's no FeatureServer layer for it, so -source=featureserver syncs
+	// requests only and leaves request_fields_YYYY untouched.
+	if opts.source == "featureserver" {
+		log.Println("skipping fields/categories import: not available via -source=featureserver")
 	} else {
-		const fieldsID = "81703e2cda974ffb8d4ba1f313d18429"
-		fields, _, err = download(ctx, fieldsID, time.Time{})
-		if err != nil {
-			return fmt.Errorf("downloading fields: %w", err)
+		var fields io.ReadCloser
+		if opts.fieldsFile != "" {
+			fields, err = os.Open(opts.fieldsFile)
+			if err != nil {
+				return fmt.Errorf("opening fields file: %w", err)
+			}
+		} else {
+			const fieldsID = "81703e2cda974ffb8d4ba1f313d18429"
+			fields, _, err = download(ctx, fieldsID, time.Time{}, opts.download)
+			if err != nil {
+				return fmt.Errorf("downloading fields: %w", err)
+			}
 		}
-	}
-	defer fields.Close()
+		defer fields.Close()
 
-	cr = csv.NewReader(fields)
-	if _, err := cr.Read(); err != nil {
-		return fmt.Errorf("reading fields header: %w", err)
-	}
-
-	fieldsYearStmts := make(map[int]*sql.Stmt)
-	fieldsYearStmt := func(year int) (*sql.Stmt, error) {
-		if stmt, ok := fieldsYearStmts[year]; ok {
-			return stmt, nil
+		cr := csv.NewReader(fields)
+		if _, err := cr.Read(); err != nil {
+			return fmt.Errorf("reading fields header: %w", err)
 		}
 
-		stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO request_fields_%d (id, category_id, category, outcome) VALUES (?, ?, ?, ?) ON CONFLICT (id, category_id) DO UPDATE SET category=excluded.category, outcome=excluded.outcome`, year))
-		if err != nil {
-			return nil, fmt.Errorf("preparing fields statement: %w", err)
-		}
-		fieldsYearStmts[year] = stmt
-		return stmt, nil
-	}
+		fieldsYearStmts := make(map[int]*sql.Stmt)
+		fieldsYearStmt := func(year int) (*sql.Stmt, error) {
+			if stmt, ok := fieldsYearStmts[year]; ok {
+				return stmt, nil
+			}
 
-	n = 0
-	var pe *csv.ParseError
-	for {
-		row, err := cr.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if errors.As(err, &pe) {
-			continue
-		}
-		if err != nil {
-			return fmt.Errorf("reading fields row: %w", err)
+			stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO request_fields_%d (id, category_id, category, outcome) VALUES (?, ?, ?, ?) ON CONFLICT (id, category_id) DO UPDATE SET category=excluded.category, outcome=excluded.outcome`, year))
+			if err != nil {
+				return nil, fmt.Errorf("preparing fields statement: %w", err)
+			}
+			fieldsYearStmts[year] = stmt
+			return stmt, nil
 		}
 
-		requestID, err := strconv.Atoi(row[0])
-		if err != nil {
-			return fmt.Errorf("parsing request ID: %w", err)
-		}
+		n = 0
+		var pe *csv.ParseError
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.As(err, &pe) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("reading fields row: %w", err)
+			}
 
-		year, ok := requestYears[requestID]
-		if !ok {
-			return fmt.Errorf("missing request year: %v", requestID)
-		}
+			requestID, err := strconv.Atoi(row[0])
+			if err != nil {
+				return fmt.Errorf("parsing request ID: %w", err)
+			}
 
-		stmt, err := fieldsYearStmt(year)
-		if err != nil {
-			return fmt.Errorf("getting fields statement: %w", err)
-		}
+			year, ok := requestYears[requestID]
+			if !ok {
+				return fmt.Errorf("missing request year: %v", requestID)
+			}
 
-		if _, err = stmt.Exec(row[0], row[1], row[2], row[3]); err != nil {
-			return fmt.Errorf("inserting fields row: %w", err)
-		}
+			stmt, err := fieldsYearStmt(year)
+			if err != nil {
+				return fmt.Errorf("getting fields statement: %w", err)
+			}
 
-		n++
-		if n%10000 == 0 {
-			log.Println("processed", n, "fields")
+			if _, err = stmt.Exec(row[0], row[1], row[2], row[3]); err != nil {
+				return fmt.Errorf("inserting fields row: %w", err)
+			}
+
+			n++
+			if n%10000 == 0 {
+				log.Println("processed", n, "fields")
+			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing transaction: %w", err)
 	}
-	return nil
-}
 
-func download(ctx context.Context, id string, lastModified time.Time) (_ io.ReadCloser, modified time.Time, _ error) {
-	currentModified, err := func() (time.Time, error) {
-		req, err := http.NewRequestWithContext(ctx, "GET", "https://www.arcgis.com/sharing/rest/content/items/"+id+"?f=json", nil)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("creating request: %w", err)
+	if opts.exportGeoJSON != "" {
+		if err := exportGeoJSON(db, opts.exportGeoJSON); err != nil {
+			return fmt.Errorf("exporting geojson: %w", err)
 		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("executing request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		b, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("reading body: %w", err)
-		}
-
-		if resp.StatusCode/100 != 2 {
-			return time.Time{}, fmt.Errorf("unexpected status code: %d -- %v", resp.StatusCode, string(b))
+	}
+	if opts.exportGPKG != "" {
+		if err := exportGeoPackage(db, opts.exportGPKG); err != nil {
+			return fmt.Errorf("exporting geopackage: %w", err)
 		}
+	}
 
-		var body struct {
-			Modified int64 `json:"modified"`
-		}
-		if err := json.Unmarshal(b, &body); err != nil {
-			return time.Time{}, fmt.Errorf("unmarshaling body: %w", err)
-		}
+	return nil
+}
 
-		return time.Unix(0, body.Modified*int64(time.Millisecond)), nil
-	}()
+func download(ctx context.Context, id string, lastModified time.Time, opts downloadOptions) (_ io.ReadCloser, modified time.Time, _ error) {
+	currentModified, err := itemModified(ctx, id, opts)
 	if err != nil {
 		return nil, time.Time{}, fmt.Errorf("getting modified time: %w", err)
 	}
@@ -379,66 +424,296 @@ func download(ctx context.Context, id string, lastModified time.Time) (_ io.Read
 
 	log.Println("downloading", id, "last modified", lastModified, "current modified", currentModified)
 
-	deadline := time.Now().Add(10 * time.Minute)
+	deadline := time.Now().Add(opts.pollTimeout)
 
 	var resultURL string
+	backoff := opts.initialBackoff
 	for time.Now().Before(deadline) {
-		u, body, err := func() (string, []byte, error) {
-			req, err := http.NewRequestWithContext(ctx, "GET", "https://hub.arcgis.com/api/download/v1/items/"+id+"/csv?redirect=false&layers=0", nil)
-			if err != nil {
-				return "", nil, fmt.Errorf("creating request: %w", err)
-			}
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				return "", nil, fmt.Errorf("executing request: %w", err)
-			}
-			defer resp.Body.Close()
-
-			b, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return "", nil, fmt.Errorf("reading body: %w", err)
-			}
-
-			if resp.StatusCode/100 != 2 {
-				return "", nil, fmt.Errorf("unexpected status code: %d -- %v", resp.StatusCode, string(b))
-			}
-
-			var body struct {
-				ResultURL string `json:"resultUrl"`
-			}
-			if err := json.Unmarshal(b, &body); err != nil {
-				return "", nil, fmt.Errorf("unmarshaling body: %w", err)
-			}
-
-			return body.ResultURL, b, nil
-		}()
+		u, retryAfter, body, err := requestExportURL(ctx, id, opts)
 		if err != nil {
-			return nil, time.Time{}, fmt.Errorf("downloading data: %w", err)
+			return nil, time.Time{}, fmt.Errorf("polling for export: %w", err)
 		}
 		if u == "" {
 			log.Println("waiting", id, "body", string(body))
+			wait := fullJitter(backoff)
+			if retryAfter > 0 {
+				wait = retryAfter
+			}
 			select {
-			case <-time.After(10 * time.Second):
+			case <-time.After(wait):
 			case <-ctx.Done():
 				return nil, time.Time{}, ctx.Err()
 			}
+			backoff = nextBackoff(backoff)
 			continue
 		}
 		resultURL = u
 		break
 	}
+	if resultURL == "" {
+		return nil, time.Time{}, fmt.Errorf("timed out after %s waiting for export %s to become ready", opts.pollTimeout, id)
+	}
 
 	log.Println("downloading", id, "from", resultURL)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", resultURL, nil)
+	body, err := fetchResumable(ctx, resultURL, opts)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("downloading result: %w", err)
+	}
+	return body, currentModified, nil
+}
+
+// itemModified fetches the ArcGIS Hub item's last-modified time, retrying
+// transient failures per opts.
+func itemModified(ctx context.Context, id string, opts downloadOptions) (time.Time, error) {
+	resp, err := doWithRetry(ctx, "GET", "https://www.arcgis.com/sharing/rest/content/items/"+id+"?f=json", opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("creating request: %w", err)
+		return time.Time{}, fmt.Errorf("reading body: %w", err)
+	}
+
+	var body struct {
+		Modified int64 `json:"modified"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return time.Time{}, fmt.Errorf("unmarshaling body: %w", err)
+	}
+
+	return time.Unix(0, body.Modified*int64(time.Millisecond)), nil
+}
+
+// requestExportURL asks the Hub export endpoint for the result URL of a
+// CSV export, returning an empty URL if the export isn't ready yet. If the
+// response carries a Retry-After header it is returned so the caller can
+// honor it instead of the regular backoff.
+func requestExportURL(ctx context.Context, id string, opts downloadOptions) (resultURL string, retryAfter time.Duration, body []byte, _ error) {
+	resp, err := doWithRetry(ctx, "GET", "https://hub.arcgis.com/api/download/v1/items/"+id+"/csv?redirect=false&layers=0", opts)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	var result struct {
+		ResultURL string `json:"resultUrl"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return "", 0, nil, fmt.Errorf("unmarshaling body: %w", err)
+	}
+
+	return result.ResultURL, retryAfterDuration(resp.Header.Get("Retry-After")), b, nil
+}
+
+// doWithRetry performs a GET request, retrying up to opts.maxRetries times
+// on network errors and 5xx responses, with exponential backoff and full
+// jitter between attempts. A 429 response is retried honoring Retry-After
+// when present. The returned response's body, if any, has a non-error,
+// non-429, non-5xx status code and must be closed by the caller.
+func doWithRetry(ctx context.Context, method, url string, opts downloadOptions) (*http.Response, error) {
+	backoff := opts.initialBackoff
+	var retryAfter time.Duration
+	var lastErr error
+	for attempt := 0; attempt <= opts.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := fullJitter(backoff)
+			if retryAfter > 0 {
+				wait, retryAfter = retryAfter, 0
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff = nextBackoff(backoff)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("executing request: %w", err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter = retryAfterDuration(resp.Header.Get("Retry-After"))
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("rate limited (429) -- %s", b)
+			continue
+		}
+		if resp.StatusCode/100 == 5 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server error: %d -- %s", resp.StatusCode, b)
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status code: %d -- %s", resp.StatusCode, b)
+		}
+
+		return resp, nil
 	}
-	resp, err := http.DefaultClient.Do(req)
+	return nil, fmt.Errorf("giving up after %d retries: %w", opts.maxRetries, lastErr)
+}
+
+// fetchResumable downloads url to a temporary file, resuming with an HTTP
+// Range request if the connection drops mid-transfer, up to
+// opts.maxRetries times. The returned ReadCloser deletes the temp file on
+// Close.
+func fetchResumable(ctx context.Context, url string, opts downloadOptions) (io.ReadCloser, error) {
+	f, err := os.CreateTemp("", "cityworks-sync-*.csv")
 	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("executing request: %w", err)
+		return nil, fmt.Errorf("creating temp file: %w", err)
+	}
+
+	var written int64
+	backoff := opts.initialBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(fullJitter(backoff)):
+			case <-ctx.Done():
+				f.Close()
+				os.Remove(f.Name())
+				return nil, ctx.Err()
+			}
+			backoff = nextBackoff(backoff)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		if written > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			if attempt >= opts.maxRetries {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, fmt.Errorf("executing request: %w", err)
+			}
+			continue
+		}
+
+		if written > 0 && resp.StatusCode == http.StatusOK {
+			// Server ignored the Range request; restart from scratch.
+			resp.Body.Close()
+			if attempt >= opts.maxRetries {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, fmt.Errorf("server does not support resume after %d attempts", attempt)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, fmt.Errorf("seeking temp file: %w", err)
+			}
+			if err := f.Truncate(0); err != nil {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, fmt.Errorf("truncating temp file: %w", err)
+			}
+			written = 0
+			continue
+		}
+		if resp.StatusCode/100 == 5 || (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent) {
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if attempt >= opts.maxRetries {
+				f.Close()
+				os.Remove(f.Name())
+				return nil, fmt.Errorf("unexpected status code: %d -- %s", resp.StatusCode, b)
+			}
+			continue
+		}
+
+		n, copyErr := io.Copy(f, resp.Body)
+		resp.Body.Close()
+		written += n
+		if copyErr == nil {
+			break
+		}
+		if attempt >= opts.maxRetries {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, fmt.Errorf("copying response body: %w", copyErr)
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("seeking temp file: %w", err)
+	}
+	return &deleteOnCloseFile{f}, nil
+}
+
+// deleteOnCloseFile wraps an *os.File so that Close also removes it from
+// disk, letting fetchResumable's temp file be used as a one-shot
+// io.ReadCloser.
+type deleteOnCloseFile struct {
+	*os.File
+}
+
+func (f *deleteOnCloseFile) Close() error {
+	name := f.Name()
+	err := f.File.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
 	}
-	return resp.Body, currentModified, nil
+	return err
+}
+
+// nextBackoff doubles d, capping it at maxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// fullJitter returns a random duration in [0, d), per the "full jitter"
+// strategy for spreading out retries.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// retryAfterDuration parses an HTTP Retry-After header value, which may be
+// either a number of seconds or an HTTP date. It returns 0 if v is empty or
+// unparseable.
+func retryAfterDuration(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 func createYearTable(tx *sql.Tx, year int) error {
@@ -448,5 +723,5 @@ func createYearTable(tx *sql.Tx, year int) error {
 	if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS request_fields_%d (id TEXT REFERENCES requests_%d (id), category_id INTEGER, category TEXT, outcome TEXT, PRIMARY KEY (id, category_id))`, year, year)); err != nil {
 		return fmt.Errorf("creating fields table: %w", err)
 	}
-	return nil
+	return applyYearTableMigrations(tx, year)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	cases := []struct {
+		in   time.Duration
+		want time.Duration
+	}{
+		{time.Second, 2 * time.Second},
+		{30 * time.Second, 60 * time.Second},
+		{45 * time.Second, maxBackoff},
+		{maxBackoff, maxBackoff},
+	}
+	for _, c := range cases {
+		if got := nextBackoff(c.in); got != c.want {
+			t.Errorf("nextBackoff(%s) = %s, want %s", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	if got := fullJitter(0); got != 0 {
+		t.Errorf("fullJitter(0) = %s, want 0", got)
+	}
+	if got := fullJitter(-time.Second); got != 0 {
+		t.Errorf("fullJitter(negative) = %s, want 0", got)
+	}
+	for i := 0; i < 100; i++ {
+		got := fullJitter(10 * time.Second)
+		if got < 0 || got >= 10*time.Second {
+			t.Fatalf("fullJitter(10s) = %s, want in [0, 10s)", got)
+		}
+	}
+}
+
+func TestRetryAfterDuration(t *testing.T) {
+	if got := retryAfterDuration(""); got != 0 {
+		t.Errorf("retryAfterDuration(\"\") = %s, want 0", got)
+	}
+	if got := retryAfterDuration("junk"); got != 0 {
+		t.Errorf("retryAfterDuration(junk) = %s, want 0", got)
+	}
+	if got := retryAfterDuration("120"); got != 120*time.Second {
+		t.Errorf("retryAfterDuration(120) = %s, want 120s", got)
+	}
+
+	future := time.Now().Add(5 * time.Minute).UTC()
+	got := retryAfterDuration(future.Format(http.TimeFormat))
+	if got < 4*time.Minute || got > 5*time.Minute {
+		t.Errorf("retryAfterDuration(HTTP date) = %s, want close to 5m", got)
+	}
+}
+
+func testDownloadOptions() downloadOptions {
+	return downloadOptions{maxRetries: 5, initialBackoff: time.Millisecond}
+}
+
+func TestDoWithRetrySucceedsAfterServerErrors(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	resp, err := doWithRetry(context.Background(), "GET", srv.URL, testDownloadOptions())
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "ok" {
+		t.Errorf("body = %q, want \"ok\"", b)
+	}
+}
+
+func TestDoWithRetryGivesUpAfterMaxRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	opts := testDownloadOptions()
+	opts.maxRetries = 2
+	if _, err := doWithRetry(context.Background(), "GET", srv.URL, opts); err == nil {
+		t.Error("doWithRetry with a permanently failing server = nil error, want error")
+	}
+}
+
+// TestFetchResumableResumesAfterDrop simulates a connection dropping
+// partway through the response body by hijacking and closing the
+// connection after writing only half the body, then serving the rest of
+// the file (via a Range request) on the retry.
+func TestFetchResumableResumesAfterDrop(t *testing.T) {
+	const body = "the quick brown fox jumps over the lazy dog"
+	half := len(body) / 2
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			hj, ok := w.(http.Hijacker)
+			if !ok {
+				t.Fatal("ResponseWriter does not support hijacking")
+			}
+			conn, buf, err := hj.Hijack()
+			if err != nil {
+				t.Fatalf("hijacking connection: %v", err)
+			}
+			defer conn.Close()
+			io.WriteString(buf, "HTTP/1.1 200 OK\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n")
+			io.WriteString(buf, body[:half])
+			buf.Flush()
+			return
+		}
+
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader != "bytes="+strconv.Itoa(half)+"-" {
+			t.Errorf("Range header = %q, want bytes=%d-", rangeHeader, half)
+		}
+		w.Header().Set("Content-Range", "bytes "+strconv.Itoa(half)+"-"+strconv.Itoa(len(body)-1)+"/"+strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+		io.WriteString(w, body[half:])
+	}))
+	defer srv.Close()
+
+	rc, err := fetchResumable(context.Background(), srv.URL, testDownloadOptions())
+	if err != nil {
+		t.Fatalf("fetchResumable: %v", err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
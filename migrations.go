@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one versioned, idempotent schema change. global, if set,
+// runs once against the database as a whole (e.g. creating a table).
+// perYearTable, if set, additionally runs against every requests_YYYY
+// table that exists, so tables created before and after the migration
+// end up with the same schema; createYearTable also runs it against a
+// table it just created, so brand new year tables never need a later
+// catch-up migration for changes already known about.
+//
+// migrations is append-only: never edit an entry once it has shipped,
+// add a new one instead.
+type migration struct {
+	version      int
+	description  string
+	global       func(tx *sql.Tx) error
+	perYearTable func(tx *sql.Tx, year int) error
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create sync_state table",
+		global: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS sync_state (requests_modified DATETIME)`)
+			return err
+		},
+	},
+	{
+		version:     2,
+		description: "create request_history_YYYY table and index",
+		perYearTable: func(tx *sql.Tx, year int) error {
+			if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS request_history_%d (id TEXT REFERENCES requests_%d (id), observed_at DATETIME, column_name TEXT, old_value TEXT, new_value TEXT)`, year, year)); err != nil {
+				return fmt.Errorf("creating history table: %w", err)
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS request_history_%d_id_observed_at ON request_history_%d (id, observed_at)`, year, year)); err != nil {
+				return fmt.Errorf("creating history index: %w", err)
+			}
+			return nil
+		},
+	},
+}
+
+// runMigrations brings db's schema up to the version at the end of
+// migrations, failing fast if the database is already at a newer
+// version than this binary knows about (e.g. it was last run with a
+// newer build).
+func runMigrations(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME)`); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	latest := 0
+	for _, m := range migrations {
+		if m.version > latest {
+			latest = m.version
+		}
+	}
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than the %d this binary supports; refusing to run against a newer schema", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.version, m.description, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs m against every existing requests_YYYY table (in
+// addition to any global change) in a single transaction, then records
+// the new schema version.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if m.global != nil {
+		if err := m.global(tx); err != nil {
+			return err
+		}
+	}
+	if m.perYearTable != nil {
+		years, err := yearTablesTx(tx)
+		if err != nil {
+			return err
+		}
+		for _, year := range years {
+			if err := m.perYearTable(tx, year); err != nil {
+				return fmt.Errorf("year %d: %w", year, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, m.version, time.Now().UTC().Truncate(time.Millisecond)); err != nil {
+		return fmt.Errorf("recording migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// applyYearTableMigrations brings a single requests_YYYY table (and its
+// companions) up to date with every registered perYearTable migration,
+// regardless of schema_migrations bookkeeping. createYearTable calls
+// this right after creating a table so it matches the schema of tables
+// that already existed when earlier migrations ran.
+func applyYearTableMigrations(tx *sql.Tx, year int) error {
+	for _, m := range migrations {
+		if m.perYearTable == nil {
+			continue
+		}
+		if err := m.perYearTable(tx, year); err != nil {
+			return fmt.Errorf("year %d: %w", year, err)
+		}
+	}
+	return nil
+}
+
+// yearTablesTx is requestYearTables, scoped to an in-progress transaction
+// so a migration sees tables created earlier in the same transaction.
+func yearTablesTx(tx *sql.Tx) ([]int, error) {
+	rows, err := tx.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE 'requests\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying year tables: %w", err)
+	}
+	defer rows.Close()
+
+	var years []int
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning table name: %w", err)
+		}
+		var year int
+		if _, err := fmt.Sscanf(name, "requests_%d", &year); err != nil {
+			continue
+		}
+		years = append(years, year)
+	}
+	return years, rows.Err()
+}
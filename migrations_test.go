@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n); err != nil {
+		t.Fatalf("checking for table %s: %v", name, err)
+	}
+	return n > 0
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		t.Fatalf("reading schema version: %v", err)
+	}
+	return v
+}
+
+func TestRunMigrationsAppliesInOrderAndIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := runMigrations(db); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+
+	latest := migrations[len(migrations)-1].version
+	if v := schemaVersion(t, db); v != latest {
+		t.Errorf("schema version = %d, want %d", v, latest)
+	}
+	if !tableExists(t, db, "sync_state") {
+		t.Error("sync_state table was not created")
+	}
+
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("counting schema_migrations rows: %v", err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+
+	// Running again must not error or reapply anything.
+	if err := runMigrations(db); err != nil {
+		t.Fatalf("second runMigrations: %v", err)
+	}
+	var appliedAgain int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&appliedAgain); err != nil {
+		t.Fatalf("counting schema_migrations rows: %v", err)
+	}
+	if appliedAgain != applied {
+		t.Errorf("second runMigrations changed schema_migrations row count: %d -> %d", applied, appliedAgain)
+	}
+}
+
+// TestRunMigrationsBackfillsDormantYearTables reproduces a database last
+// touched by a binary that only knew about migration 1 (no
+// request_history_YYYY table), with a requests_YYYY table that predates
+// the history feature and saw no activity in the meantime. Upgrading and
+// running migrations again must still create its history table.
+func TestRunMigrationsBackfillsDormantYearTables(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME)`); err != nil {
+		t.Fatalf("seeding schema_migrations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (1, ?)`, time.Now().UTC()); err != nil {
+		t.Fatalf("seeding migration 1: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE sync_state (requests_modified DATETIME)`); err != nil {
+		t.Fatalf("seeding sync_state: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE requests_2019 (id TEXT PRIMARY KEY)`); err != nil {
+		t.Fatalf("seeding dormant year table: %v", err)
+	}
+
+	if tableExists(t, db, "request_history_2019") {
+		t.Fatal("request_history_2019 already exists before migrating")
+	}
+
+	if err := runMigrations(db); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+
+	if !tableExists(t, db, "request_history_2019") {
+		t.Error("runMigrations did not backfill request_history_2019 for the dormant year table")
+	}
+}
+
+func TestRunMigrationsRefusesDowngrade(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME)`); err != nil {
+		t.Fatalf("seeding schema_migrations: %v", err)
+	}
+	future := len(migrations) + 1
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, future, time.Now().UTC()); err != nil {
+		t.Fatalf("seeding future migration: %v", err)
+	}
+
+	err := runMigrations(db)
+	if err == nil {
+		t.Fatal("runMigrations against a newer schema version = nil error, want error")
+	}
+	if !strings.Contains(err.Error(), "newer") {
+		t.Errorf("error = %q, want it to mention the schema being newer than supported", err)
+	}
+}
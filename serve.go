@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runServe implements the "serve" subcommand: a read-only HTTP API over a
+// synced database, for dashboards and other callers that shouldn't need to
+// learn the requests_YYYY table layout.
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var dbPath, addr string
+	fs.StringVar(&dbPath, "db", "data.db", "database file path")
+	fs.StringVar(&addr, "addr", ":8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	srv := &requestsServer{db: db}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /requests", srv.handleRequests)
+	mux.HandleFunc("GET /stats", srv.handleStats)
+
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+	log.Println("serving on", addr)
+
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return httpServer.Close()
+	}
+}
+
+type requestsServer struct {
+	db *sql.DB
+}
+
+// requestsFilter holds the parsed query parameters for GET /requests.
+type requestsFilter struct {
+	year     int // 0 means all years
+	category string
+	status   string
+	bbox     *bbox
+	limit    int
+	cursor   *requestsCursor
+}
+
+type bbox struct {
+	minLon, minLat, maxLon, maxLat float64
+}
+
+// requestsCursor identifies the last row of a previous page; rows are
+// ordered by (initiated, id), so resuming after it is a simple keyset
+// comparison.
+type requestsCursor struct {
+	initiated string
+	id        string
+}
+
+func encodeCursor(c requestsCursor) string {
+	return base64.URLEncoding.EncodeToString([]byte(c.initiated + "\x00" + c.id))
+}
+
+func decodeCursor(s string) (requestsCursor, error) {
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return requestsCursor{}, fmt.Errorf("decoding cursor: %w", err)
+	}
+	// Split on the last separator: id is the field after it, so an
+	// initiated value containing a NUL byte still round-trips.
+	i := strings.LastIndexByte(string(b), 0)
+	if i < 0 {
+		return requestsCursor{}, fmt.Errorf("malformed cursor")
+	}
+	return requestsCursor{initiated: string(b[:i]), id: string(b[i+1:])}, nil
+}
+
+const (
+	defaultRequestsLimit = 500
+	maxRequestsLimit     = 2000
+)
+
+func parseRequestsFilter(r *http.Request) (requestsFilter, error) {
+	q := r.URL.Query()
+
+	f := requestsFilter{
+		category: q.Get("category"),
+		status:   q.Get("status"),
+		limit:    defaultRequestsLimit,
+	}
+
+	if v := q.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid year: %w", err)
+		}
+		f.year = year
+	}
+
+	if v := q.Get("bbox"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			return f, fmt.Errorf("bbox must have 4 comma-separated values")
+		}
+		var coords [4]float64
+		for i, p := range parts {
+			c, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return f, fmt.Errorf("invalid bbox coordinate %q: %w", p, err)
+			}
+			coords[i] = c
+		}
+		f.bbox = &bbox{minLon: coords[0], minLat: coords[1], maxLon: coords[2], maxLat: coords[3]}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid limit: %w", err)
+		}
+		f.limit = limit
+	}
+	if f.limit <= 0 || f.limit > maxRequestsLimit {
+		f.limit = min(max(f.limit, 1), maxRequestsLimit)
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.cursor = &c
+	}
+
+	return f, nil
+}
+
+// queryRequests runs f against the requested year tables (or every year
+// table if f.year is 0), returning up to f.limit+1 rows ordered by
+// (initiated, id) so the caller can tell whether another page follows.
+func queryRequests(db *sql.DB, f requestsFilter) ([]requestFeature, error) {
+	years, err := requestYearTables(db)
+	if err != nil {
+		return nil, err
+	}
+	if f.year != 0 {
+		found := false
+		for _, y := range years {
+			if y == f.year {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil
+		}
+		years = []int{f.year}
+	}
+	if len(years) == 0 {
+		return nil, nil
+	}
+
+	const cols = "id, initiated, closed, first_observed, description, initiator, priority, address, community, district, category, resolution, latitude, longitude, status, department, work_order, project_name"
+	var parts []string
+	for _, y := range years {
+		parts = append(parts, fmt.Sprintf("SELECT %s FROM requests_%d", cols, y))
+	}
+
+	var where []string
+	var args []any
+	if f.category != "" {
+		where = append(where, "category = ?")
+		args = append(args, f.category)
+	}
+	if f.status != "" {
+		where = append(where, "LOWER(status) = LOWER(?)")
+		args = append(args, f.status)
+	}
+	if f.bbox != nil {
+		where = append(where, "longitude BETWEEN ? AND ? AND latitude BETWEEN ? AND ?")
+		args = append(args, f.bbox.minLon, f.bbox.maxLon, f.bbox.minLat, f.bbox.maxLat)
+	}
+	if f.cursor != nil {
+		where = append(where, "(initiated, id) > (?, ?)")
+		args = append(args, f.cursor.initiated, f.cursor.id)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM (%s)", cols, strings.Join(parts, " UNION ALL "))
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY initiated, id LIMIT ?"
+	args = append(args, f.limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying requests: %w", err)
+	}
+	defer rows.Close()
+
+	var features []requestFeature
+	for rows.Next() {
+		var rf requestFeature
+		var lat, lon sql.NullString
+		if err := rows.Scan(&rf.ID, &rf.Initiated, &rf.Closed, &rf.FirstObserved, &rf.Description, &rf.Initiator, &rf.Priority, &rf.Address, &rf.Community, &rf.District, &rf.Category, &rf.Resolution, &lat, &lon, &rf.Status, &rf.Department, &rf.WorkOrder, &rf.ProjectName); err != nil {
+			return nil, fmt.Errorf("scanning request: %w", err)
+		}
+		// A request geocoded with no coordinates is stored as the empty
+		// string, which does not parse as a float; treat it the same as
+		// 0,0, which callers already take to mean "no geometry".
+		rf.Latitude, _ = strconv.ParseFloat(lat.String, 64)
+		rf.Longitude, _ = strconv.ParseFloat(lon.String, 64)
+		features = append(features, rf)
+	}
+	return features, rows.Err()
+}
+
+func (s *requestsServer) handleRequests(w http.ResponseWriter, r *http.Request) {
+	f, err := parseRequestsFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	modified, etag, ok := s.checkConditional(w, r)
+	if !ok {
+		return
+	}
+
+	features, err := queryRequests(s.db, f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var nextCursor string
+	if len(features) > f.limit {
+		last := features[f.limit-1]
+		nextCursor = encodeCursor(requestsCursor{initiated: last.Initiated, id: last.ID})
+		features = features[:f.limit]
+	}
+
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	switch negotiateFormat(r) {
+	case "csv":
+		writeRequestsCSV(w, features)
+	case "geojson":
+		writeRequestsGeoJSON(w, features, nextCursor)
+	default:
+		writeRequestsJSON(w, features, nextCursor)
+	}
+}
+
+// negotiateFormat inspects Accept for the response format; unrecognized or
+// absent Accept values default to JSON.
+func negotiateFormat(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "csv"):
+		return "csv"
+	case strings.Contains(accept, "geo+json"), strings.Contains(accept, "geojson"):
+		return "geojson"
+	default:
+		return "json"
+	}
+}
+
+func writeRequestsJSON(w http.ResponseWriter, features []requestFeature, nextCursor string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Requests   []map[string]any `json:"requests"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}{
+		Requests:   propertiesOf(features),
+		NextCursor: nextCursor,
+	})
+}
+
+func propertiesOf(features []requestFeature) []map[string]any {
+	props := make([]map[string]any, len(features))
+	for i, f := range features {
+		props[i] = f.properties()
+	}
+	return props
+}
+
+func writeRequestsCSV(w http.ResponseWriter, features []requestFeature) {
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "initiated", "closed", "first_observed", "description", "initiator", "priority", "address", "community", "district", "category", "resolution", "latitude", "longitude", "status", "department", "work_order", "project_name"})
+	for _, f := range features {
+		cw.Write([]string{
+			f.ID, f.Initiated, f.Closed, f.FirstObserved, f.Description, f.Initiator, f.Priority, f.Address,
+			f.Community, f.District, f.Category, f.Resolution,
+			strconv.FormatFloat(f.Latitude, 'f', -1, 64), strconv.FormatFloat(f.Longitude, 'f', -1, 64),
+			f.Status, f.Department, f.WorkOrder, f.ProjectName,
+		})
+	}
+	cw.Flush()
+}
+
+func writeRequestsGeoJSON(w http.ResponseWriter, features []requestFeature, nextCursor string) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	fc := geoJSONFeatureCollection{
+		Type:      "FeatureCollection",
+		Generated: time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, f := range features {
+		gf := geoJSONFeature{Type: "Feature", Properties: f.properties()}
+		if f.Longitude != 0 || f.Latitude != 0 {
+			gf.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{f.Longitude, f.Latitude}}
+		}
+		fc.Features = append(fc.Features, gf)
+	}
+	json.NewEncoder(w).Encode(struct {
+		geoJSONFeatureCollection
+		NextCursor string `json:"next_cursor,omitempty"`
+	}{geoJSONFeatureCollection: fc, NextCursor: nextCursor})
+}
+
+// checkConditional reads sync_state.requests_modified and honors
+// If-None-Match / If-Modified-Since, writing a 304 and returning ok=false
+// if the client's cached copy is current.
+func (s *requestsServer) checkConditional(w http.ResponseWriter, r *http.Request) (modified time.Time, etag string, ok bool) {
+	if err := s.db.QueryRow("SELECT requests_modified FROM sync_state").Scan(&modified); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return time.Time{}, "", false
+	}
+	if modified.IsZero() {
+		return modified, "", true
+	}
+
+	etag = fmt.Sprintf(`"%d"`, modified.UnixNano())
+	if inm := r.Header.Get("If-None-Match"); inm != "" && inm == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return modified, etag, false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !modified.After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return modified, etag, false
+		}
+	}
+	return modified, etag, true
+}
+
+// statsResult is the /stats response: request counts broken down by
+// category, community, and year.
+type statsResult struct {
+	ByCategory  map[string]int `json:"by_category"`
+	ByCommunity map[string]int `json:"by_community"`
+	ByYear      map[string]int `json:"by_year"`
+}
+
+func (s *requestsServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	years, err := requestYearTables(s.db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := statsResult{
+		ByCategory:  make(map[string]int),
+		ByCommunity: make(map[string]int),
+		ByYear:      make(map[string]int),
+	}
+
+	for _, year := range years {
+		total, err := addGroupCounts(s.db, year, "category", result.ByCategory)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := addGroupCounts(s.db, year, "community", result.ByCommunity); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.ByYear[strconv.Itoa(year)] = total
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// addGroupCounts tallies requests_YYYY rows by column into counts and
+// returns the year's total row count.
+func addGroupCounts(db *sql.DB, year int, column string, counts map[string]int) (int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s, COUNT(*) FROM requests_%d GROUP BY %s", column, year, column))
+	if err != nil {
+		return 0, fmt.Errorf("querying %s counts: %w", column, err)
+	}
+	defer rows.Close()
+
+	var total int
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return 0, fmt.Errorf("scanning %s count: %w", column, err)
+		}
+		counts[value] += count
+		total += count
+	}
+	return total, rows.Err()
+}
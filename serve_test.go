@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cases := []requestsCursor{
+		{initiated: "2024-01-02T03:04:05Z", id: "123"},
+		{initiated: "", id: ""},
+		{initiated: "has\x00null", id: "456"},
+	}
+	for _, c := range cases {
+		got, err := decodeCursor(encodeCursor(c))
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%+v)): %v", c, err)
+		}
+		if got != c {
+			t.Errorf("round trip of %+v = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	if _, err := decodeCursor("not-base64!!"); err == nil {
+		t.Error("decodeCursor(invalid base64) = nil error, want error")
+	}
+	noSeparator := base64.URLEncoding.EncodeToString([]byte("no-null-byte"))
+	if _, err := decodeCursor(noSeparator); err == nil {
+		t.Error("decodeCursor(no separator) = nil error, want error")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestsCSVItemID is the ArcGIS Hub item backing the CSV bulk export of
+// Cityworks requests.
+const requestsCSVItemID = "d2b7dd138adb468293183926a1a7a81c"
+
+// Record is a single Cityworks request, keyed by the field names used in
+// the CSV export (REQUEST_ID, DATE_INITIATED, LATITUDE, ...). Sources
+// normalize their native representation into these keys, including
+// formatting date fields as "1/2/2006 3:04:05 PM" in the Halifax time
+// zone, so the rest of the pipeline can treat every Source identically.
+type Record map[string]string
+
+// Source produces Cityworks requests for the sync pipeline. Implementations
+// exist for the ArcGIS Hub CSV bulk export and for paging an ArcGIS
+// FeatureServer layer directly.
+type Source interface {
+	// Modified returns the time the source's underlying data was last
+	// changed. The caller compares it against the previously recorded
+	// sync_state.requests_modified to decide whether a sync is needed.
+	Modified(ctx context.Context) (time.Time, error)
+
+	// Fetch returns the records considered changed since since. A zero
+	// since means "everything". The returned sequence is lazily read;
+	// iteration stops early if the consumer returns false or errors.
+	Fetch(ctx context.Context, since time.Time) (iter.Seq2[Record, error], error)
+}
+
+// newSource builds the Source selected by opts.source.
+func newSource(opts syncOptions, loc *time.Location) (Source, error) {
+	switch opts.source {
+	case "", "csv":
+		return &csvSource{itemID: requestsCSVItemID, file: opts.requestsFile, opts: opts.download}, nil
+	case "featureserver":
+		if opts.featureServerURL == "" {
+			return nil, fmt.Errorf("-featureserver-url is required for -source=featureserver")
+		}
+		return &featureServerSource{baseURL: opts.featureServerURL, opts: opts.download, loc: loc}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", opts.source)
+	}
+}
+
+// csvSource is the original ArcGIS Hub CSV bulk export, optionally
+// overridden with a local file for testing.
+type csvSource struct {
+	itemID string
+	file   string
+	opts   downloadOptions
+}
+
+func (s *csvSource) Modified(ctx context.Context) (time.Time, error) {
+	if s.file != "" {
+		return time.Now().UTC(), nil
+	}
+	return itemModified(ctx, s.itemID, s.opts)
+}
+
+// Fetch ignores since: the Hub CSV export always carries the full
+// dataset, with no way to filter by modification time. Callers rely on
+// Modified instead to decide whether a sync is needed at all.
+func (s *csvSource) Fetch(ctx context.Context, _ time.Time) (iter.Seq2[Record, error], error) {
+	var body io.ReadCloser
+	if s.file != "" {
+		f, err := os.Open(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("opening data file: %w", err)
+		}
+		body = f
+	} else {
+		r, _, err := download(ctx, s.itemID, time.Time{}, s.opts)
+		if err != nil {
+			return nil, fmt.Errorf("downloading data: %w", err)
+		}
+		body = r
+	}
+	return csvRecords(body), nil
+}
+
+func csvRecords(body io.ReadCloser) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		defer body.Close()
+
+		cr := csv.NewReader(body)
+		// REQUEST_ID DATE_INITIATED DATE_CLOSED DESCRIPTION INITIATED_BY PRIORITY ADDRESS COMMUNITY DISTRICT REQUEST_CATEGORY RESOLUTION LATITUDE LONGITUDE STATUS DEPT_RESPONSIBILITY WORK_ORDER ObjectId PROJECT_NAME
+		header, err := cr.Read()
+		if err != nil {
+			yield(nil, fmt.Errorf("reading header: %w", err))
+			return
+		}
+
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("reading row: %w", err))
+				return
+			}
+
+			rec := make(Record, len(header))
+			for i, h := range header {
+				if i < len(row) {
+					rec[h] = row[i]
+				}
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// featureServerSource pulls changed Cityworks requests directly from an
+// ArcGIS FeatureServer layer, filtering by EditDate so only records
+// modified since the last sync are transferred.
+type featureServerSource struct {
+	baseURL string
+	opts    downloadOptions
+	loc     *time.Location
+}
+
+// featureServerPageSize is the page size requested per query; the server
+// may still cap it lower via exceededTransferLimit.
+const featureServerPageSize = 2000
+
+// Modified always reports the current time: a FeatureServer layer exposes
+// no single "last modified" timestamp comparable to a Hub item's, so the
+// delta filtering happens in Fetch via the EditDate where clause instead.
+func (s *featureServerSource) Modified(context.Context) (time.Time, error) {
+	return time.Now().UTC(), nil
+}
+
+func (s *featureServerSource) Fetch(ctx context.Context, since time.Time) (iter.Seq2[Record, error], error) {
+	return func(yield func(Record, error) bool) {
+		offset := 0
+		for {
+			page, err := s.queryPage(ctx, since, offset)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, feat := range page.Features {
+				rec := make(Record, len(feat.Attributes)+2)
+				for k, v := range feat.Attributes {
+					rec[k] = formatFeatureServerValue(k, v, s.loc)
+				}
+				if feat.Geometry != nil {
+					rec["LONGITUDE"] = strconv.FormatFloat(feat.Geometry.X, 'f', -1, 64)
+					rec["LATITUDE"] = strconv.FormatFloat(feat.Geometry.Y, 'f', -1, 64)
+				}
+				if !yield(rec, nil) {
+					return
+				}
+			}
+
+			if !page.ExceededTransferLimit {
+				return
+			}
+			offset += len(page.Features)
+		}
+	}, nil
+}
+
+type featureServerPage struct {
+	Features              []featureServerFeature `json:"features"`
+	ExceededTransferLimit bool                   `json:"exceededTransferLimit"`
+	Error                 *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type featureServerFeature struct {
+	Attributes map[string]any `json:"attributes"`
+	Geometry   *struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	} `json:"geometry"`
+}
+
+func (s *featureServerSource) queryPage(ctx context.Context, since time.Time, offset int) (*featureServerPage, error) {
+	q := url.Values{}
+	q.Set("f", "json")
+	q.Set("where", fmt.Sprintf("EditDate > %d", since.UnixMilli()))
+	q.Set("outFields", "*")
+	q.Set("returnGeometry", "true")
+	q.Set("resultOffsetType", "standard")
+	q.Set("resultOffset", strconv.Itoa(offset))
+	q.Set("resultRecordCount", strconv.Itoa(featureServerPageSize))
+
+	queryURL := strings.TrimRight(s.baseURL, "/") + "/query?" + q.Encode()
+
+	resp, err := doWithRetry(ctx, "GET", queryURL, s.opts)
+	if err != nil {
+		return nil, fmt.Errorf("querying feature server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading feature server response: %w", err)
+	}
+
+	var page featureServerPage
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, fmt.Errorf("unmarshaling feature server response: %w", err)
+	}
+	if page.Error != nil {
+		return nil, fmt.Errorf("feature server error %d: %s", page.Error.Code, page.Error.Message)
+	}
+	return &page, nil
+}
+
+// featureServerDateFields are the attributes that arrive from FeatureServer
+// as epoch-millisecond numbers and need reformatting into the
+// "1/2/2006 3:04:05 PM" Halifax-local string the rest of the pipeline
+// expects, matching the CSV export's format.
+var featureServerDateFields = map[string]bool{
+	"DATE_INITIATED": true,
+	"DATE_CLOSED":    true,
+}
+
+func formatFeatureServerValue(key string, v any, loc *time.Location) string {
+	if v == nil {
+		return ""
+	}
+	if featureServerDateFields[key] {
+		ms, ok := v.(float64)
+		if !ok || ms == 0 {
+			return ""
+		}
+		return time.UnixMilli(int64(ms)).In(loc).Format("1/2/2006 3:04:05 PM")
+	}
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
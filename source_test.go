@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFeatureServerSourceFetchPagesUntilTransferLimitNotExceeded(t *testing.T) {
+	pages := []featureServerPage{
+		{
+			Features: []featureServerFeature{
+				{Attributes: map[string]any{"REQUEST_ID": "1"}},
+				{Attributes: map[string]any{"REQUEST_ID": "2"}},
+			},
+			ExceededTransferLimit: true,
+		},
+		{
+			Features: []featureServerFeature{
+				{Attributes: map[string]any{"REQUEST_ID": "3"}},
+			},
+			ExceededTransferLimit: false,
+		},
+	}
+
+	var gotOffsets []string
+	var gotWhere string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOffsets = append(gotOffsets, r.URL.Query().Get("resultOffset"))
+		gotWhere = r.URL.Query().Get("where")
+
+		page := pages[0]
+		pages = pages[1:]
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	s := &featureServerSource{baseURL: srv.URL, opts: testDownloadOptions(), loc: time.UTC}
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	records, err := s.Fetch(context.Background(), since)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	var ids []string
+	for rec, err := range records {
+		if err != nil {
+			t.Fatalf("iterating records: %v", err)
+		}
+		ids = append(ids, rec["REQUEST_ID"])
+	}
+
+	if want := []string{"1", "2", "3"}; !equalStrings(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if want := []string{"0", "2"}; !equalStrings(gotOffsets, want) {
+		t.Errorf("resultOffset values = %v, want %v", gotOffsets, want)
+	}
+	if want := "EditDate > " + strconv.FormatInt(since.UnixMilli(), 10); gotWhere != want {
+		t.Errorf("where = %q, want %q", gotWhere, want)
+	}
+}
+
+func TestFeatureServerSourceFetchStopsOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	opts := testDownloadOptions()
+	opts.maxRetries = 0
+	s := &featureServerSource{baseURL: srv.URL, opts: opts, loc: time.UTC}
+
+	records, err := s.Fetch(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	var gotErr error
+	for _, err := range records {
+		if err != nil {
+			gotErr = err
+			break
+		}
+	}
+	if gotErr == nil {
+		t.Error("expected an error from a permanently failing server, got nil")
+	}
+}
+
+func TestFormatFeatureServerValue(t *testing.T) {
+	loc, err := time.LoadLocation("America/Halifax")
+	if err != nil {
+		t.Skipf("no tzdata available: %v", err)
+	}
+
+	t.Run("date field", func(t *testing.T) {
+		// 2024-06-15T12:00:00Z, a date during Atlantic Daylight Time (UTC-3).
+		ms := float64(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC).UnixMilli())
+		got := formatFeatureServerValue("DATE_INITIATED", ms, loc)
+		want := "6/15/2024 9:00:00 AM"
+		if got != want {
+			t.Errorf("formatFeatureServerValue(DATE_INITIATED) = %q, want %q", got, want)
+		}
+	})
+
+	if got := formatFeatureServerValue("DATE_INITIATED", nil, loc); got != "" {
+		t.Errorf("formatFeatureServerValue(nil) = %q, want \"\"", got)
+	}
+	if got := formatFeatureServerValue("DATE_INITIATED", float64(0), loc); got != "" {
+		t.Errorf("formatFeatureServerValue(0) = %q, want \"\"", got)
+	}
+	if got := formatFeatureServerValue("STATUS", "Open", loc); got != "Open" {
+		t.Errorf("formatFeatureServerValue(string) = %q, want \"Open\"", got)
+	}
+	if got := formatFeatureServerValue("OBJECTID", float64(42), loc); got != "42" {
+		t.Errorf("formatFeatureServerValue(float64) = %q, want \"42\"", got)
+	}
+	if got := formatFeatureServerValue("FLAG", true, loc); got != "true" {
+		t.Errorf("formatFeatureServerValue(bool) = %q, want \"true\"", got)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}